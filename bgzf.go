@@ -0,0 +1,275 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// BGZF (RFC 1952 gzip with a "BC" extra subfield carrying the compressed
+// size of the block, as defined by htslib/samtools) lets a .2bit file be
+// shipped compressed while still allowing random access: every block is an
+// independently decompressible gzip member, so a reader only has to inflate
+// the blocks that overlap the bytes it actually needs.
+
+const (
+	bgzfID1      = 0x1f
+	bgzfID2      = 0x8b
+	bgzfCM       = 8 // deflate
+	bgzfFEXTRA   = 1 << 2
+	bgzfSubfield = "BC"
+)
+
+// isBGZF peeks at the start of r to check for the gzip magic and BC extra
+// subfield that identify a BGZF stream, then restores the reader's
+// position.
+func isBGZF(r io.ReadSeeker) (bool, error) {
+	cur, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return false, err
+	}
+	defer r.Seek(cur, io.SeekStart)
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return false, err
+	}
+
+	_, _, err = readBGZFBlockHeader(r)
+
+	return err == nil, nil
+}
+
+// bgzfBlock describes the location of one compressed BGZF block and the
+// uncompressed bytes it decodes to.
+type bgzfBlock struct {
+	compressedOffset   int64
+	compressedSize     int64
+	uncompressedOffset int64
+	uncompressedSize   int64
+}
+
+// readBGZFBlockHeader reads and validates the 12-byte gzip header plus
+// extra field of the BGZF block starting at the current position of r and
+// returns the BSIZE (total block size minus one) carried by the BC
+// subfield, along with the number of header bytes consumed (12+XLEN).
+func readBGZFBlockHeader(r io.Reader) (bsize, headerLen int, err error) {
+	hdr := make([]byte, 12)
+	if _, err := io.ReadFull(r, hdr); err != nil {
+		return 0, 0, err
+	}
+
+	if hdr[0] != bgzfID1 || hdr[1] != bgzfID2 || hdr[2] != bgzfCM || hdr[3]&bgzfFEXTRA == 0 {
+		return 0, 0, fmt.Errorf("Not a BGZF block")
+	}
+
+	xlen := int(binary.LittleEndian.Uint16(hdr[10:12]))
+	extra := make([]byte, xlen)
+	if _, err := io.ReadFull(r, extra); err != nil {
+		return 0, 0, err
+	}
+
+	for i := 0; i+4 <= len(extra); {
+		slen := int(binary.LittleEndian.Uint16(extra[i+2 : i+4]))
+		if string(extra[i:i+2]) == bgzfSubfield && slen == 2 {
+			bsize = int(binary.LittleEndian.Uint16(extra[i+4 : i+6]))
+			return bsize, 12 + xlen, nil
+		}
+		i += 4 + slen
+	}
+
+	return 0, 0, fmt.Errorf("BGZF block missing BC subfield")
+}
+
+// buildBGZFIndex scans a BGZF stream from the start, recording the
+// compressed and uncompressed boundaries of every block. This lets random
+// access translate an uncompressed byte offset into the single block that
+// needs to be inflated to reach it.
+func buildBGZFIndex(r io.ReadSeeker) ([]bgzfBlock, error) {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	var blocks []bgzfBlock
+	var coffset, uoffset int64
+
+	for {
+		bsize, headerLen, err := readBGZFBlockHeader(r)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Failed to parse BGZF block at offset %d: %s", coffset, err)
+		}
+
+		blockSize := int64(bsize) + 1
+		cdataSize := blockSize - int64(headerLen) - 8
+		if cdataSize < 0 {
+			return nil, fmt.Errorf("Invalid BGZF block size at offset %d", coffset)
+		}
+
+		if _, err := r.Seek(cdataSize, io.SeekCurrent); err != nil {
+			return nil, fmt.Errorf("Failed to skip BGZF block data: %s", err)
+		}
+
+		trailer := make([]byte, 8)
+		if _, err := io.ReadFull(r, trailer); err != nil {
+			return nil, fmt.Errorf("Failed to read BGZF block trailer: %s", err)
+		}
+
+		isize := int64(binary.LittleEndian.Uint32(trailer[4:8]))
+
+		blocks = append(blocks, bgzfBlock{
+			compressedOffset:   coffset,
+			compressedSize:     blockSize,
+			uncompressedOffset: uoffset,
+			uncompressedSize:   isize,
+		})
+
+		coffset += blockSize
+		uoffset += isize
+	}
+
+	return blocks, nil
+}
+
+// bgzfReader presents the uncompressed byte stream of a BGZF file as an
+// io.ReadSeeker, inflating only the block needed to satisfy the current
+// Read or Seek.
+type bgzfReader struct {
+	r      io.ReadSeeker
+	blocks []bgzfBlock
+	pos    int64
+	curBlk int
+	cache  []byte
+}
+
+// newBGZFReader builds a block index for r and returns a reader over its
+// decompressed bytes.
+func newBGZFReader(r io.ReadSeeker) (*bgzfReader, error) {
+	blocks, err := buildBGZFIndex(r)
+	if err != nil {
+		return nil, err
+	}
+
+	return &bgzfReader{r: r, blocks: blocks, curBlk: -1}, nil
+}
+
+// size returns the total uncompressed size of the stream.
+func (b *bgzfReader) size() int64 {
+	if len(b.blocks) == 0 {
+		return 0
+	}
+	last := b.blocks[len(b.blocks)-1]
+	return last.uncompressedOffset + last.uncompressedSize
+}
+
+// findBlock returns the index of the block covering uncompressed offset
+// pos via binary search over the sorted block index.
+func (b *bgzfReader) findBlock(pos int64) (int, error) {
+	lo, hi := 0, len(b.blocks)-1
+	for lo <= hi {
+		mid := (lo + hi) / 2
+		blk := b.blocks[mid]
+		switch {
+		case pos < blk.uncompressedOffset:
+			hi = mid - 1
+		case pos >= blk.uncompressedOffset+blk.uncompressedSize:
+			lo = mid + 1
+		default:
+			return mid, nil
+		}
+	}
+
+	return -1, fmt.Errorf("Offset %d out of range", pos)
+}
+
+// loadBlock inflates block i into the cache, unless it is already cached.
+func (b *bgzfReader) loadBlock(i int) error {
+	if b.curBlk == i {
+		return nil
+	}
+
+	blk := b.blocks[i]
+	if _, err := b.r.Seek(blk.compressedOffset, io.SeekStart); err != nil {
+		return err
+	}
+
+	raw := make([]byte, blk.compressedSize)
+	if _, err := io.ReadFull(b.r, raw); err != nil {
+		return fmt.Errorf("Failed to read BGZF block: %s", err)
+	}
+
+	gz, err := gzip.NewReader(bytes.NewReader(raw))
+	if err != nil {
+		return fmt.Errorf("Failed to inflate BGZF block: %s", err)
+	}
+
+	data, err := io.ReadAll(gz)
+	if err != nil {
+		return fmt.Errorf("Failed to inflate BGZF block: %s", err)
+	}
+
+	b.cache = data
+	b.curBlk = i
+
+	return nil
+}
+
+// Read implements io.Reader, inflating only the block that covers the
+// current position.
+func (b *bgzfReader) Read(p []byte) (int, error) {
+	if b.pos >= b.size() {
+		return 0, io.EOF
+	}
+
+	idx, err := b.findBlock(b.pos)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := b.loadBlock(idx); err != nil {
+		return 0, err
+	}
+
+	off := int(b.pos - b.blocks[idx].uncompressedOffset)
+	n := copy(p, b.cache[off:])
+	b.pos += int64(n)
+
+	return n, nil
+}
+
+// Seek implements io.Seeker over the uncompressed byte stream.
+func (b *bgzfReader) Seek(offset int64, whence int) (int64, error) {
+	var newPos int64
+
+	switch whence {
+	case io.SeekStart:
+		newPos = offset
+	case io.SeekCurrent:
+		newPos = b.pos + offset
+	case io.SeekEnd:
+		newPos = b.size() + offset
+	default:
+		return 0, fmt.Errorf("Invalid whence: %d", whence)
+	}
+
+	if newPos < 0 {
+		return 0, fmt.Errorf("Negative position")
+	}
+
+	b.pos = newPos
+
+	return b.pos, nil
+}
+
+// IsCompressed returns true if r was opened from a BGZF-compressed 2bit
+// file.
+func (r *Reader) IsCompressed() bool {
+	return r.bgzf != nil
+}
@@ -0,0 +1,180 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+	"bytes"
+	"compress/flate"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"os"
+)
+
+// bgzfBlockSize is the maximum number of uncompressed bytes packed into a
+// single BGZF block, matching htslib's bgzf_write block size so output
+// plays nicely with samtools and friends.
+const bgzfBlockSize = 0xff00
+
+// bgzfEOFMarker is the well-known empty BGZF block that terminates a
+// well-formed stream, as emitted by htslib/samtools.
+var bgzfEOFMarker = []byte{
+	0x1f, 0x8b, 0x08, 0x04, 0x00, 0x00, 0x00, 0x00, 0x00, 0xff, 0x06, 0x00,
+	0x42, 0x43, 0x02, 0x00, 0x1b, 0x00, 0x03, 0x00, 0x00, 0x00, 0x00, 0x00,
+	0x00, 0x00, 0x00, 0x00,
+}
+
+// CompressFile reads the plain 2bit file at src and writes a BGZF-compressed
+// copy to dst, together with a samtools-compatible ".gzi" index alongside
+// it (dst + ".gzi"), so the result can be opened directly by NewReader or
+// by any BGZF-aware tool.
+func CompressFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	var coffset, uoffset uint64
+	var gzi gziIndex
+	var pending *gziEntry
+
+	buf := make([]byte, bgzfBlockSize)
+	for {
+		n, err := io.ReadFull(in, buf)
+		if n > 0 {
+			bsize, werr := writeBGZFBlock(out, buf[:n])
+			if werr != nil {
+				return werr
+			}
+
+			// A .gzi entry records a boundary *between* two real blocks, so
+			// the one pending from the previous iteration only gets written
+			// once we know a following block actually exists; the implicit
+			// first block at (0,0) is never stored, and neither is a
+			// trailing entry for whatever comes after the last real block
+			// (the EOF marker is not a data block).
+			if pending != nil {
+				gzi.entries = append(gzi.entries, *pending)
+			}
+
+			coffset += uint64(bsize)
+			uoffset += uint64(n)
+			pending = &gziEntry{compressedOffset: coffset, uncompressedOffset: uoffset}
+		}
+
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if _, err := out.Write(bgzfEOFMarker); err != nil {
+		return err
+	}
+
+	return gzi.writeFile(dst + ".gzi")
+}
+
+// writeBGZFBlock deflates data into a single self-contained BGZF block and
+// writes it to w, returning the total number of bytes written.
+func writeBGZFBlock(w io.Writer, data []byte) (int, error) {
+	var deflated bytes.Buffer
+
+	fw, err := flate.NewWriter(&deflated, flate.DefaultCompression)
+	if err != nil {
+		return 0, err
+	}
+	if _, err := fw.Write(data); err != nil {
+		return 0, err
+	}
+	if err := fw.Close(); err != nil {
+		return 0, err
+	}
+
+	const headerLen = 12
+	const extraLen = 6 // SI1, SI2, SLEN(2), BSIZE(2)
+	blockSize := headerLen + extraLen + deflated.Len() + 8
+
+	hdr := make([]byte, headerLen)
+	hdr[0], hdr[1] = bgzfID1, bgzfID2
+	hdr[2] = bgzfCM
+	hdr[3] = bgzfFEXTRA
+	hdr[9] = 0xff // OS = unknown
+	binary.LittleEndian.PutUint16(hdr[10:12], extraLen)
+
+	extra := make([]byte, extraLen)
+	extra[0], extra[1] = 'B', 'C'
+	binary.LittleEndian.PutUint16(extra[2:4], 2)
+	binary.LittleEndian.PutUint16(extra[4:6], uint16(blockSize-1))
+
+	if _, err := w.Write(hdr); err != nil {
+		return 0, err
+	}
+	if _, err := w.Write(extra); err != nil {
+		return 0, err
+	}
+	if _, err := w.Write(deflated.Bytes()); err != nil {
+		return 0, err
+	}
+
+	trailer := make([]byte, 8)
+	binary.LittleEndian.PutUint32(trailer[0:4], crc32.ChecksumIEEE(data))
+	binary.LittleEndian.PutUint32(trailer[4:8], uint32(len(data)))
+	if _, err := w.Write(trailer); err != nil {
+		return 0, err
+	}
+
+	return blockSize, nil
+}
+
+// gziEntry records the compressed/uncompressed byte offsets of a BGZF
+// block boundary, as stored in a ".gzi" index.
+type gziEntry struct {
+	compressedOffset   uint64
+	uncompressedOffset uint64
+}
+
+// gziIndex is the in-memory form of a samtools-compatible ".gzi" file: a
+// uint64 entry count followed by that many (compressed, uncompressed)
+// offset pairs, all little-endian.
+type gziIndex struct {
+	entries []gziEntry
+}
+
+func (idx *gziIndex) writeFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	buf := make([]byte, 8)
+	binary.LittleEndian.PutUint64(buf, uint64(len(idx.entries)))
+	if _, err := f.Write(buf); err != nil {
+		return err
+	}
+
+	for _, e := range idx.entries {
+		binary.LittleEndian.PutUint64(buf, e.compressedOffset)
+		if _, err := f.Write(buf); err != nil {
+			return err
+		}
+		binary.LittleEndian.PutUint64(buf, e.uncompressedOffset)
+		if _, err := f.Write(buf); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
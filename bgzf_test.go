@@ -0,0 +1,220 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestBGZFRoundTrip(t *testing.T) {
+	seqs := map[string]string{
+		"chr1": "ACGTACGTNNNNacgtACGTnnnnACGTACGTAC",
+		"chr2": "NNNNNNNNNNACGTACGTacgtacgtACGTTTTTGGGGCCCCAAAA",
+	}
+
+	plain, err := os.CreateTemp("", "twobit-bgzf-test-*.2bit")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %s", err)
+	}
+	defer os.Remove(plain.Name())
+	defer plain.Close()
+
+	w := NewWriter(plain)
+	for _, name := range []string{"chr1", "chr2"} {
+		if err := w.Add(name, []byte(seqs[name])); err != nil {
+			t.Fatalf("Failed to add %s: %s", name, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Failed to close writer: %s", err)
+	}
+
+	gzPath := plain.Name() + ".gz"
+	if err := CompressFile(plain.Name(), gzPath); err != nil {
+		t.Fatalf("Failed to compress: %s", err)
+	}
+	defer os.Remove(gzPath)
+	defer os.Remove(gzPath + ".gzi")
+
+	f, err := os.Open(gzPath)
+	if err != nil {
+		t.Fatalf("Failed to open compressed file: %s", err)
+	}
+	defer f.Close()
+
+	r, err := NewReader(f)
+	if err != nil {
+		t.Fatalf("Failed to read compressed twobit file: %s", err)
+	}
+
+	if !r.IsCompressed() {
+		t.Errorf("expected IsCompressed() to be true")
+	}
+
+	for name, expected := range seqs {
+		got, err := r.Read(name)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %s", name, err)
+		}
+		if string(got) != expected {
+			t.Errorf("%s: expected %s got %s", name, expected, got)
+		}
+	}
+
+	got, err := r.ReadRange("chr2", 10, 26)
+	if err != nil {
+		t.Fatalf("Failed to read range: %s", err)
+	}
+	if string(got) != seqs["chr2"][10:26] {
+		t.Errorf("expected %s got %s", seqs["chr2"][10:26], got)
+	}
+}
+
+// readGziFile parses a raw ".gzi" file into the (compressed, uncompressed)
+// offset pairs it stores, independent of gziIndex.writeFile.
+func readGziFile(t *testing.T, path string) []gziEntry {
+	t.Helper()
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read %s: %s", path, err)
+	}
+
+	if len(raw) < 8 {
+		t.Fatalf("%s too short to hold an entry count", path)
+	}
+
+	count := binary.LittleEndian.Uint64(raw[0:8])
+	raw = raw[8:]
+
+	if uint64(len(raw)) != count*16 {
+		t.Fatalf("%s: entry count %d doesn't match remaining byte count %d", path, count, len(raw))
+	}
+
+	entries := make([]gziEntry, count)
+	for i := range entries {
+		entries[i].compressedOffset = binary.LittleEndian.Uint64(raw[i*16 : i*16+8])
+		entries[i].uncompressedOffset = binary.LittleEndian.Uint64(raw[i*16+8 : i*16+16])
+	}
+
+	return entries
+}
+
+func TestCompressFileGziBlockBoundaries(t *testing.T) {
+	// CompressFile reads the plain 2bit file's raw bytes, and DNA is packed
+	// 2 bits/base, so the sequence needs to be several times bgzfBlockSize
+	// bases long for the packed 2bit file itself to span more than one
+	// BGZF block.
+	seq := bytes.Repeat([]byte("ACGT"), bgzfBlockSize*2)
+
+	plain, err := os.CreateTemp("", "twobit-gzi-test-*.2bit")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %s", err)
+	}
+	defer os.Remove(plain.Name())
+	defer plain.Close()
+
+	w := NewWriter(plain)
+	if err := w.Add("chr1", seq); err != nil {
+		t.Fatalf("Failed to add sequence: %s", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Failed to close writer: %s", err)
+	}
+
+	gzPath := plain.Name() + ".gz"
+	if err := CompressFile(plain.Name(), gzPath); err != nil {
+		t.Fatalf("Failed to compress: %s", err)
+	}
+	defer os.Remove(gzPath)
+	defer os.Remove(gzPath + ".gzi")
+
+	f, err := os.Open(gzPath)
+	if err != nil {
+		t.Fatalf("Failed to open compressed file: %s", err)
+	}
+	defer f.Close()
+
+	blocks, err := buildBGZFIndex(f)
+	if err != nil {
+		t.Fatalf("Failed to walk BGZF blocks: %s", err)
+	}
+
+	// buildBGZFIndex also picks up the trailing empty EOF marker block
+	// (BGZF's own "this stream is done" block); it carries no sequence
+	// data and a samtools .gzi index never records an entry for it.
+	var dataBlocks []bgzfBlock
+	for _, b := range blocks {
+		if b.uncompressedSize > 0 {
+			dataBlocks = append(dataBlocks, b)
+		}
+	}
+
+	if len(dataBlocks) < 2 {
+		t.Fatalf("test setup produced only %d real BGZF block(s), need at least 2", len(dataBlocks))
+	}
+
+	entries := readGziFile(t, gzPath+".gzi")
+
+	wantCount := len(dataBlocks) - 1
+	if len(entries) != wantCount {
+		t.Fatalf("expected %d .gzi entries (one per boundary between %d real blocks), got %d", wantCount, len(dataBlocks), len(entries))
+	}
+
+	for i, e := range entries {
+		blk := dataBlocks[i]
+		wantCoffset := uint64(blk.compressedOffset + blk.compressedSize)
+		wantUoffset := uint64(blk.uncompressedOffset + blk.uncompressedSize)
+
+		if e.compressedOffset != wantCoffset || e.uncompressedOffset != wantUoffset {
+			t.Errorf("entry %d: expected (coffset=%d, uoffset=%d) got (coffset=%d, uoffset=%d)",
+				i, wantCoffset, wantUoffset, e.compressedOffset, e.uncompressedOffset)
+		}
+	}
+
+	// The last block written has no following boundary, so nothing should
+	// point at or past where the BGZF EOF marker begins.
+	last := dataBlocks[len(dataBlocks)-1]
+	eofOffset := uint64(last.compressedOffset + last.compressedSize)
+	for i, e := range entries {
+		if e.compressedOffset >= eofOffset {
+			t.Errorf("entry %d: compressedOffset %d points at or past the EOF marker (offset %d)", i, e.compressedOffset, eofOffset)
+		}
+	}
+}
+
+func TestIsCompressedPlainFile(t *testing.T) {
+	plain, err := os.CreateTemp("", "twobit-plain-test-*.2bit")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %s", err)
+	}
+	defer os.Remove(plain.Name())
+	defer plain.Close()
+
+	w := NewWriter(plain)
+	if err := w.Add("chr1", []byte("ACGTACGT")); err != nil {
+		t.Fatalf("Failed to add sequence: %s", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Failed to close writer: %s", err)
+	}
+
+	if _, err := plain.Seek(0, io.SeekStart); err != nil {
+		t.Fatalf("Failed to seek temp file: %s", err)
+	}
+
+	r, err := NewReader(plain)
+	if err != nil {
+		t.Fatalf("Failed to read twobit file: %s", err)
+	}
+
+	if r.IsCompressed() {
+		t.Errorf("expected IsCompressed() to be false for a plain 2bit file")
+	}
+}
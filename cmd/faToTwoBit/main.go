@@ -0,0 +1,89 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+// Command faToTwoBit converts one or more FASTA files into a single 2bit
+// file, with flag semantics compatible with UCSC's faToTwoBit.
+package main
+
+import (
+	"compress/gzip"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/mendelics/twobit"
+	"github.com/mendelics/twobit/fasta"
+)
+
+func main() {
+	noMask := flag.Bool("noMask", false, "Convert sequence to all upper case, discarding soft-masking")
+	long := flag.Bool("long", false, "Accepted for faToTwoBit flag compatibility; output never has a 4Gb size limit here")
+	stripVersion := flag.Bool("stripVersion", false, "Strip off version number after '.' for each sequence")
+
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: faToTwoBit [options] in.fa[.gz] [in2.fa[.gz] ...] out.2bit")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	_ = *long
+
+	args := flag.Args()
+	if len(args) < 2 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	inputs := args[:len(args)-1]
+	outPath := args[len(args)-1]
+
+	out, err := os.Create(outPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create %s: %s\n", outPath, err)
+		os.Exit(1)
+	}
+	defer out.Close()
+
+	w := twobit.NewWriter(out)
+
+	opts := fasta.Options{NoMask: *noMask, StripVersion: *stripVersion}
+
+	for _, path := range inputs {
+		if err := encodeFile(w, path, opts); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+
+	if err := w.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to write %s: %s\n", outPath, err)
+		os.Exit(1)
+	}
+}
+
+// encodeFile streams one FASTA (optionally gzip-compressed) input into w.
+func encodeFile(w *twobit.Writer, path string, opts fasta.Options) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("Failed to open %s: %s", path, err)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("Failed to open %s: %s", path, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	if err := fasta.Encode(w, r, opts); err != nil {
+		return fmt.Errorf("Failed to encode %s: %s", path, err)
+	}
+
+	return nil
+}
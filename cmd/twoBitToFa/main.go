@@ -0,0 +1,126 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+// Command twoBitToFa converts a 2bit file back to FASTA, with flag
+// semantics compatible with UCSC's twoBitToFa.
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/mendelics/twobit"
+	"github.com/mendelics/twobit/fasta"
+)
+
+func main() {
+	noMask := flag.Bool("noMask", false, "Convert sequence to all upper case")
+	long := flag.Bool("long", false, "Write each sequence on a single line instead of wrapping at 50 bases")
+	seqName := flag.String("seq", "", "Restrict output to this one sequence")
+	start := flag.Int("start", 0, "Start coordinate, with -seq, of the region to extract")
+	end := flag.Int("end", 0, "End coordinate, with -seq, of the region to extract (0 means to the end)")
+	bedPath := flag.String("bed", "", "Restrict output to the regions listed in this BED file")
+
+	flag.Usage = func() {
+		fmt.Fprintln(os.Stderr, "usage: twoBitToFa [options] in.2bit out.fa")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) != 2 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	in, err := os.Open(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open %s: %s\n", args[0], err)
+		os.Exit(1)
+	}
+	defer in.Close()
+
+	r, err := twobit.NewReader(in)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to read %s: %s\n", args[0], err)
+		os.Exit(1)
+	}
+
+	out, err := os.Create(args[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create %s: %s\n", args[1], err)
+		os.Exit(1)
+	}
+	defer out.Close()
+
+	opts := fasta.DecodeOptions{NoMask: *noMask}
+	if *long {
+		opts.LineWidth = math.MaxInt32
+	}
+
+	switch {
+	case *bedPath != "":
+		regions, err := readBED(*bedPath)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		opts.Regions = regions
+	case *seqName != "":
+		opts.Regions = []fasta.Region{{Name: *seqName, Start: *start, End: *end}}
+	}
+
+	if err := fasta.Decode(out, r, opts); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// readBED parses the first three columns (chrom, start, end) of a BED
+// file into fasta.Regions, as used by the -bed flag.
+func readBED(path string) ([]fasta.Region, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to open %s: %s", path, err)
+	}
+	defer f.Close()
+
+	var regions []fasta.Region
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("Invalid BED line: %s", line)
+		}
+
+		start, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("Invalid BED start in line: %s", line)
+		}
+
+		end, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("Invalid BED end in line: %s", line)
+		}
+
+		regions = append(regions, fasta.Region{Name: fields[0], Start: start, End: end})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("Failed to read %s: %s", path, err)
+	}
+
+	return regions, nil
+}
@@ -0,0 +1,120 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package fasta
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/mendelics/twobit"
+)
+
+// defaultLineWidth is the classic FASTA wrap width used when
+// DecodeOptions.LineWidth is left at its zero value.
+const defaultLineWidth = 50
+
+// Region names a half-open [Start, End) interval of a single named
+// sequence, as used by DecodeOptions.Regions / the -bed flag.
+type Region struct {
+	Name  string
+	Start int
+	End   int
+}
+
+// DecodeOptions controls how Decode exports a twobit.Reader to FASTA.
+type DecodeOptions struct {
+	// LineWidth is the number of bases written per output line. The zero
+	// value uses defaultLineWidth; pass a very large value to mirror
+	// twoBitToFa -long, which writes every sequence on a single line.
+	LineWidth int
+
+	// NoMask discards soft-masking, writing every base upper-case
+	// regardless of what the 2bit file records, mirroring
+	// twoBitToFa -noMask.
+	NoMask bool
+
+	// Regions restricts output to the given sub-ranges, in order,
+	// mirroring twoBitToFa's -seq/-start/-end/-bed flags. A nil or empty
+	// slice exports every sequence in the file in full.
+	Regions []Region
+}
+
+// Decode streams the sequences of r to w as FASTA, streaming so
+// multi-gigabyte genomes are never held fully in memory.
+func Decode(w io.Writer, r *twobit.Reader, opts DecodeOptions) error {
+	lineWidth := opts.LineWidth
+	if lineWidth <= 0 {
+		lineWidth = defaultLineWidth
+	}
+
+	restricted := len(opts.Regions) > 0
+
+	regions := opts.Regions
+	if !restricted {
+		names := r.Names()
+		sort.Strings(names)
+
+		for _, name := range names {
+			length, err := r.Length(name)
+			if err != nil {
+				return err
+			}
+			regions = append(regions, Region{Name: name, Start: 0, End: length})
+		}
+	}
+
+	bw := bufio.NewWriter(w)
+
+	for _, region := range regions {
+		// A region with both Start and End left at zero means "the whole
+		// sequence" (the same sentinel ReadRange itself honors), so it gets
+		// a plain ">name" header rather than ">name:start-end" even when it
+		// arrived via opts.Regions, matching twoBitToFa's -seq (with no
+		// -start/-end) behavior.
+		whole := !restricted || (region.Start == 0 && region.End == 0)
+
+		seq, err := r.ReadRange(region.Name, region.Start, region.End)
+		if err != nil {
+			return fmt.Errorf("Failed to read %s:%d-%d: %s", region.Name, region.Start, region.End, err)
+		}
+
+		if opts.NoMask {
+			seq = bytes.ToUpper(seq)
+		}
+
+		header := fmt.Sprintf(">%s", region.Name)
+		if !whole {
+			// region.End may still be the 0 sentinel ("to the end"); read
+			// the resolved end back off what ReadRange actually returned
+			// rather than re-deriving it with a second lookup.
+			end := region.End
+			if end == 0 {
+				end = region.Start + len(seq)
+			}
+			header = fmt.Sprintf(">%s:%d-%d", region.Name, region.Start, end)
+		}
+		if _, err := fmt.Fprintln(bw, header); err != nil {
+			return err
+		}
+
+		for i := 0; i < len(seq); i += lineWidth {
+			end := i + lineWidth
+			if end > len(seq) {
+				end = len(seq)
+			}
+			if _, err := bw.Write(seq[i:end]); err != nil {
+				return err
+			}
+			if err := bw.WriteByte('\n'); err != nil {
+				return err
+			}
+		}
+	}
+
+	return bw.Flush()
+}
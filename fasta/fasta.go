@@ -0,0 +1,148 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+// Package fasta streams FASTA sequence data into and out of the twobit
+// format.
+package fasta
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/mendelics/twobit"
+)
+
+// AmbiguityPolicy controls how IUPAC ambiguity codes other than N are
+// handled when importing FASTA.
+type AmbiguityPolicy int
+
+const (
+	// AmbiguityToN converts any non-ACGTN IUPAC code to N, matching
+	// UCSC faToTwoBit's default behavior.
+	AmbiguityToN AmbiguityPolicy = iota
+	// AmbiguityError fails the encode if a non-ACGTN IUPAC code is
+	// encountered. The 2bit format has no way to represent anything
+	// other than the four bases and N, so there is no "keep" option.
+	AmbiguityError
+)
+
+// Options controls how Encode imports FASTA into a twobit.Writer.
+type Options struct {
+	// NoMask discards soft-masking (lower-case) information: every base
+	// is written upper-case and no mBlocks are recorded, mirroring
+	// faToTwoBit -noMask.
+	NoMask bool
+
+	// StripVersion drops a trailing ".N" version suffix from each
+	// sequence name, mirroring faToTwoBit -stripVersion.
+	StripVersion bool
+
+	// Ambiguity controls how non-N IUPAC ambiguity codes are handled.
+	// The zero value, AmbiguityToN, matches faToTwoBit.
+	Ambiguity AmbiguityPolicy
+}
+
+// Encode streams FASTA records from r into w, calling w.Add once per
+// record so multi-gigabyte genomes are never held fully in memory.
+func Encode(w *twobit.Writer, r io.Reader, opts Options) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<30)
+
+	var name string
+	var seq []byte
+	seen := false
+
+	flush := func() error {
+		if !seen {
+			return nil
+		}
+		return w.Add(name, seq)
+	}
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if strings.HasPrefix(line, ">") {
+			if err := flush(); err != nil {
+				return fmt.Errorf("Failed to add %s: %s", name, err)
+			}
+
+			name = parseName(line, opts.StripVersion)
+			seq = seq[:0]
+			seen = true
+
+			continue
+		}
+
+		for i := 0; i < len(line); i++ {
+			base, err := normalizeBase(line[i], opts)
+			if err != nil {
+				return fmt.Errorf("%s: %s", name, err)
+			}
+			seq = append(seq, base)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("Failed to read FASTA: %s", err)
+	}
+
+	if err := flush(); err != nil {
+		return fmt.Errorf("Failed to add %s: %s", name, err)
+	}
+
+	return nil
+}
+
+// parseName extracts the sequence name from a FASTA header line (the
+// token up to the first whitespace, after the leading '>'), optionally
+// stripping a trailing ".N" version suffix.
+func parseName(line string, stripVersion bool) string {
+	name := strings.TrimPrefix(line, ">")
+	if i := strings.IndexAny(name, " \t"); i >= 0 {
+		name = name[:i]
+	}
+
+	if stripVersion {
+		if i := strings.LastIndexByte(name, '.'); i >= 0 {
+			if _, err := strconv.Atoi(name[i+1:]); err == nil {
+				name = name[:i]
+			}
+		}
+	}
+
+	return name
+}
+
+// normalizeBase upper-cases b, maps non-ACGTN IUPAC codes to N according
+// to opts.Ambiguity (or fails if opts.Ambiguity is AmbiguityError, since
+// the 2bit format cannot represent them), and restores soft-masking
+// unless opts.NoMask is set.
+func normalizeBase(b byte, opts Options) (byte, error) {
+	lower := b >= 'a' && b <= 'z'
+
+	base := b
+	if lower {
+		base -= 'a' - 'A'
+	}
+
+	switch base {
+	case twobit.BASE_A, twobit.BASE_C, twobit.BASE_G, twobit.BASE_T, twobit.BASE_N:
+		// already a plain base
+	default:
+		if opts.Ambiguity == AmbiguityError {
+			return 0, fmt.Errorf("Cannot encode IUPAC ambiguity code %q: 2bit only supports ACGTN", b)
+		}
+		base = twobit.BASE_N
+	}
+
+	if opts.NoMask || !lower {
+		return base, nil
+	}
+
+	return base + ('a' - 'A'), nil
+}
@@ -0,0 +1,222 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package fasta
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/mendelics/twobit"
+)
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	input := ">chr1 some description\n" +
+		"ACGTACGTNNNNacgtACGT\n" +
+		"nnnnACGTACGTAC\n" +
+		">chr2\n" +
+		"TTTTGGGGCCCCAAAA\n"
+
+	f, err := os.CreateTemp("", "fasta-roundtrip-*.2bit")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %s", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	w := twobit.NewWriter(f)
+	if err := Encode(w, strings.NewReader(input), Options{}); err != nil {
+		t.Fatalf("Failed to encode: %s", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Failed to close writer: %s", err)
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatalf("Failed to seek temp file: %s", err)
+	}
+
+	r, err := twobit.NewReader(f)
+	if err != nil {
+		t.Fatalf("Failed to read back twobit file: %s", err)
+	}
+
+	var out bytes.Buffer
+	if err := Decode(&out, r, DecodeOptions{LineWidth: 1000}); err != nil {
+		t.Fatalf("Failed to decode: %s", err)
+	}
+
+	expected := ">chr1\nACGTACGTNNNNacgtACGTnnnnACGTACGTAC\n>chr2\nTTTTGGGGCCCCAAAA\n"
+	if out.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
+
+func TestEncodeAmbiguityAndNoMask(t *testing.T) {
+	input := ">chr1\nACRYSWacgt\n"
+
+	f, err := os.CreateTemp("", "fasta-ambiguity-*.2bit")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %s", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	w := twobit.NewWriter(f)
+	if err := Encode(w, strings.NewReader(input), Options{NoMask: true}); err != nil {
+		t.Fatalf("Failed to encode: %s", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Failed to close writer: %s", err)
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatalf("Failed to seek temp file: %s", err)
+	}
+
+	r, err := twobit.NewReader(f)
+	if err != nil {
+		t.Fatalf("Failed to read back twobit file: %s", err)
+	}
+
+	got, err := r.Read("chr1")
+	if err != nil {
+		t.Fatalf("Failed to read chr1: %s", err)
+	}
+
+	expected := "ACNNNNACGT"
+	if string(got) != expected {
+		t.Errorf("expected %s got %s", expected, got)
+	}
+}
+
+func TestEncodeAmbiguityError(t *testing.T) {
+	input := ">chr1\nACRYSW\n"
+
+	f, err := os.CreateTemp("", "fasta-ambiguity-error-*.2bit")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %s", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	w := twobit.NewWriter(f)
+	err = Encode(w, strings.NewReader(input), Options{Ambiguity: AmbiguityError})
+	if err == nil {
+		t.Fatalf("expected an error encoding an ambiguity code with AmbiguityError")
+	}
+}
+
+func TestDecodeLineWidth(t *testing.T) {
+	f, err := os.CreateTemp("", "fasta-linewidth-*.2bit")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %s", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	w := twobit.NewWriter(f)
+	if err := w.Add("chr1", []byte("ACGTACGTAC")); err != nil {
+		t.Fatalf("Failed to add sequence: %s", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Failed to close writer: %s", err)
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatalf("Failed to seek temp file: %s", err)
+	}
+
+	r, err := twobit.NewReader(f)
+	if err != nil {
+		t.Fatalf("Failed to read back twobit file: %s", err)
+	}
+
+	var out bytes.Buffer
+	if err := Decode(&out, r, DecodeOptions{LineWidth: 4}); err != nil {
+		t.Fatalf("Failed to decode: %s", err)
+	}
+
+	expected := ">chr1\nACGT\nACGT\nAC\n"
+	if out.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
+
+func TestDecodeRegions(t *testing.T) {
+	f, err := os.CreateTemp("", "fasta-regions-*.2bit")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %s", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	w := twobit.NewWriter(f)
+	if err := w.Add("chr1", []byte("ACGTACGTAC")); err != nil {
+		t.Fatalf("Failed to add sequence: %s", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Failed to close writer: %s", err)
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatalf("Failed to seek temp file: %s", err)
+	}
+
+	r, err := twobit.NewReader(f)
+	if err != nil {
+		t.Fatalf("Failed to read back twobit file: %s", err)
+	}
+
+	var out bytes.Buffer
+	opts := DecodeOptions{LineWidth: 1000, Regions: []Region{{Name: "chr1", Start: 2, End: 6}}}
+	if err := Decode(&out, r, opts); err != nil {
+		t.Fatalf("Failed to decode: %s", err)
+	}
+
+	expected := ">chr1:2-6\nGTAC\n"
+	if out.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
+
+func TestDecodeWholeSequenceRegion(t *testing.T) {
+	f, err := os.CreateTemp("", "fasta-whole-region-*.2bit")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %s", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	w := twobit.NewWriter(f)
+	if err := w.Add("chr1", []byte("ACGTACGTAC")); err != nil {
+		t.Fatalf("Failed to add sequence: %s", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Failed to close writer: %s", err)
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatalf("Failed to seek temp file: %s", err)
+	}
+
+	r, err := twobit.NewReader(f)
+	if err != nil {
+		t.Fatalf("Failed to read back twobit file: %s", err)
+	}
+
+	var out bytes.Buffer
+	opts := DecodeOptions{LineWidth: 1000, Regions: []Region{{Name: "chr1"}}}
+	if err := Decode(&out, r, opts); err != nil {
+		t.Fatalf("Failed to decode: %s", err)
+	}
+
+	expected := ">chr1\nACGTACGTAC\n"
+	if out.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
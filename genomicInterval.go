@@ -6,12 +6,13 @@ import (
 	"strings"
 )
 
-// GenomicInterval - Receives chr, start, end and returns genome reference.
-func (service *twobitResults) GenomicInterval(chr string, start, end int) (string, error) {
-	if chr == "" {
-		return "", errors.New("GenomicInterval can't accept blank chromosome")
-	}
+// errGenomicIntervalBlankChr is returned when a genomic interval lookup is
+// given an empty chromosome name.
+var errGenomicIntervalBlankChr = errors.New("GenomicInterval can't accept blank chromosome")
 
+// normalizeChrName adds the "chr" prefix expected by the 2bit index when
+// missing, and maps the common "chrMT" mitochondrial alias to "chrM".
+func normalizeChrName(chr string) string {
 	chrName := chr
 
 	if !strings.HasPrefix(chr, "chr") {
@@ -22,11 +23,20 @@ func (service *twobitResults) GenomicInterval(chr string, start, end int) (strin
 		chrName = "chrM"
 	}
 
+	return chrName
+}
+
+// GenomicInterval - Receives chr, start, end and returns genome reference.
+func (service *twobitResults) GenomicInterval(chr string, start, end int) (string, error) {
+	if chr == "" {
+		return "", errGenomicIntervalBlankChr
+	}
+
 	if start == end {
 		return "", nil
 	}
 
-	seq, err := service.tb.ReadRange(chrName, start, end)
+	seq, err := service.tb.ReadRange(normalizeChrName(chr), start, end)
 
 	return string(seq), err
 }
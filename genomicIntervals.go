@@ -0,0 +1,57 @@
+package twobit
+
+import "sort"
+
+// IntervalRequest is a single genomic interval lookup, as passed in bulk to
+// GenomicIntervals.
+type IntervalRequest struct {
+	Chr   string
+	Start int
+	End   int
+}
+
+// IntervalResult is the outcome of resolving the IntervalRequest at the
+// same index in the slice passed to GenomicIntervals.
+type IntervalResult struct {
+	Seq string
+	Err error
+}
+
+// GenomicIntervals resolves a batch of genomic intervals, grouping requests
+// by chromosome and visiting each chromosome in start order. This turns a
+// typical variant annotation workload - thousands of short lookups spread
+// across a VCF - into near-sequential I/O per chromosome instead of
+// repeated independent GenomicInterval calls.
+func (service *twobitResults) GenomicIntervals(reqs []IntervalRequest) ([]IntervalResult, error) {
+	results := make([]IntervalResult, len(reqs))
+
+	byChr := make(map[string][]int)
+	for i, req := range reqs {
+		if req.Chr == "" {
+			results[i] = IntervalResult{Err: errGenomicIntervalBlankChr}
+			continue
+		}
+
+		chrName := normalizeChrName(req.Chr)
+		byChr[chrName] = append(byChr[chrName], i)
+	}
+
+	for chrName, idxs := range byChr {
+		sort.Slice(idxs, func(a, b int) bool {
+			return reqs[idxs[a]].Start < reqs[idxs[b]].Start
+		})
+
+		for _, i := range idxs {
+			req := reqs[i]
+
+			if req.Start == req.End {
+				continue
+			}
+
+			seq, err := service.tb.ReadRange(chrName, req.Start, req.End)
+			results[i] = IntervalResult{Seq: string(seq), Err: err}
+		}
+	}
+
+	return results, nil
+}
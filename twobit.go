@@ -11,6 +11,7 @@ import (
 	"encoding/binary"
 	"fmt"
 	"io"
+	"sync"
 )
 
 // 2bit header
@@ -30,27 +31,28 @@ type Block struct {
 
 // seqRecord stores sequence record from the file index
 type seqRecord struct {
-	dnaSize  uint32
-	nBlocks  []*Block
-	mBlocks  []*Block
-	reserved uint32
-	sequence []byte
+	dnaSize   uint32
+	nBlocks   []*Block
+	mBlocks   []*Block
+	reserved  uint32
+	dnaOffset int64 // absolute offset of the packed DNA payload
+	sequence  []byte
 }
 
 // TwoBit stores the file index and header information of the 2bit file
 type twoBit struct {
 	reader  io.ReadSeeker
+	bgzf    *bgzfReader
+	dna     io.ReaderAt
 	hdr     header
 	index   map[string]int
+	mu      sync.RWMutex
 	records map[string]*seqRecord
 }
 
 // Reader reads twobits
 type Reader twoBit
 
-// Writer writes twobits
-type Writer twoBit
-
 func init() {
 	NT2BYTES = make([]byte, 256)
 	NT2BYTES[BASE_N] = uint8(0)
@@ -166,11 +168,45 @@ func (r *Reader) parseHeader() error {
 	return nil
 }
 
-// Parse the nBlock and mBlock coordinates
-func (r *Reader) parseBlockCoords() ([]*Block, error) {
+// raCursor is a sequential view over an io.ReaderAt. Each parseRecord call
+// gets its own cursor and scratch reads, so no state is shared between
+// concurrent callers.
+type raCursor struct {
+	ra  io.ReaderAt
+	off int64
+}
+
+func (c *raCursor) read(p []byte) error {
+	n, err := c.ra.ReadAt(p, c.off)
+	c.off += int64(n)
+	return err
+}
+
+// lockedReaderAt adapts an io.ReadSeeker that has no native ReadAt support
+// into a goroutine-safe io.ReaderAt by serializing seek-then-read under a
+// mutex. Concurrent access is then correct but not parallel; callers that
+// need parallelism should pass a reader (e.g. *os.File) that implements
+// io.ReaderAt natively.
+type lockedReaderAt struct {
+	mu sync.Mutex
+	rs io.ReadSeeker
+}
+
+func (l *lockedReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if _, err := l.rs.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	return io.ReadFull(l.rs, p)
+}
+
+// Parse the nBlock and mBlock coordinates from cur
+func (r *Reader) parseBlockCoords(cur *raCursor) ([]*Block, error) {
 	buf := make([]byte, 4)
-	_, err := r.reader.Read(buf)
-	if err != nil {
+	if err := cur.read(buf); err != nil {
 		return nil, fmt.Errorf("Failed to read blockCount: %s", err)
 	}
 
@@ -178,8 +214,7 @@ func (r *Reader) parseBlockCoords() ([]*Block, error) {
 
 	starts := make([]uint32, count)
 	for i := range starts {
-		_, err := r.reader.Read(buf)
-		if err != nil {
+		if err := cur.read(buf); err != nil {
 			return nil, fmt.Errorf("Failed to block start: %s", err)
 		}
 		starts[i] = r.hdr.byteOrder.Uint32(buf)
@@ -187,8 +222,7 @@ func (r *Reader) parseBlockCoords() ([]*Block, error) {
 
 	sizes := make([]uint32, count)
 	for i := range sizes {
-		_, err := r.reader.Read(buf)
-		if err != nil {
+		if err := cur.read(buf); err != nil {
 			return nil, fmt.Errorf("Failed to block size: %s", err)
 		}
 		sizes[i] = r.hdr.byteOrder.Uint32(buf)
@@ -203,54 +237,69 @@ func (r *Reader) parseBlockCoords() ([]*Block, error) {
 	return blocks, nil
 }
 
-// Parse the sequence record information
-func (r *Reader) parseRecord(name string, coords bool) (*seqRecord, error) {
-	rec := new(seqRecord)
+// parseRecord returns the seqRecord for name, parsing it via ReadAt on
+// first access and caching the result so later lookups (and concurrent
+// callers) never touch the underlying reader's cursor.
+func (r *Reader) parseRecord(name string) (*seqRecord, error) {
+	r.mu.RLock()
+	if rec, ok := r.records[name]; ok {
+		r.mu.RUnlock()
+		return rec, nil
+	}
+	r.mu.RUnlock()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if rec, ok := r.records[name]; ok {
+		return rec, nil
+	}
 
 	offset, ok := r.index[name]
 	if !ok {
 		return nil, fmt.Errorf("Invalid sequence name: %s", name)
 	}
 
-	r.reader.Seek(int64(offset), 0)
+	cur := &raCursor{ra: r.dna, off: int64(offset)}
+
+	rec := new(seqRecord)
 
 	buf := make([]byte, 4)
-	_, err := r.reader.Read(buf)
-	if err != nil {
+	if err := cur.read(buf); err != nil {
 		return nil, fmt.Errorf("Failed to read dnaSize: %s", err)
 	}
-
 	rec.dnaSize = r.hdr.byteOrder.Uint32(buf)
 
-	if coords {
-		rec.nBlocks, err = r.parseBlockCoords()
-		if err != nil {
-			return nil, fmt.Errorf("Failed to read nBlocks: %s", err)
-		}
-
-		rec.mBlocks, err = r.parseBlockCoords()
-		if err != nil {
-			return nil, fmt.Errorf("Failed to read mBlocks: %s", err)
-		}
+	var err error
+	rec.nBlocks, err = r.parseBlockCoords(cur)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read nBlocks: %s", err)
+	}
 
-		_, err = r.reader.Read(buf)
-		if err != nil {
-			return nil, fmt.Errorf("Failed to read reserved: %s", err)
-		}
+	rec.mBlocks, err = r.parseBlockCoords(cur)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to read mBlocks: %s", err)
+	}
 
-		rec.reserved = r.hdr.byteOrder.Uint32(buf)
+	if err := cur.read(buf); err != nil {
+		return nil, fmt.Errorf("Failed to read reserved: %s", err)
+	}
+	rec.reserved = r.hdr.byteOrder.Uint32(buf)
 
-		if rec.reserved != uint32(0) {
-			return nil, fmt.Errorf("Invalid reserved")
-		}
+	if rec.reserved != uint32(0) {
+		return nil, fmt.Errorf("Invalid reserved")
 	}
 
+	rec.dnaOffset = cur.off
+
+	r.records[name] = rec
+
 	return rec, nil
 }
 
 // NBlocks - Return blocks of Ns in sequence with name
 func (r *Reader) NBlocks(name string) ([]*Block, error) {
-	rec, err := r.parseRecord(name, true)
+	rec, err := r.parseRecord(name)
 	if err != nil {
 		return nil, err
 	}
@@ -265,7 +314,7 @@ func (r *Reader) Read(name string) ([]byte, error) {
 
 // ReadRange - Read sequence from start to end.
 func (r *Reader) ReadRange(name string, start, end int) ([]byte, error) {
-	rec, err := r.parseRecord(name, true)
+	rec, err := r.parseRecord(name)
 	if err != nil {
 		return nil, err
 	}
@@ -293,6 +342,8 @@ func (r *Reader) ReadRange(name string, start, end int) ([]byte, error) {
 
 	bases = end - start
 	size := packedSize(bases)
+
+	byteOffset := int64(0)
 	if start > 0 {
 		shift := packedSize(start)
 		if start%4 != 0 {
@@ -300,37 +351,24 @@ func (r *Reader) ReadRange(name string, start, end int) ([]byte, error) {
 			size++
 		}
 
-		r.reader.Seek(int64(shift), 1)
+		byteOffset = int64(shift)
 	}
 
-	dna := make([]byte, size*4)
-	chunks := size / defaultBufSize
-	if size%defaultBufSize > 0 {
-		chunks++
+	// Each call gets its own scratch buffer and reads through ReadAt at an
+	// absolute offset, so no cursor is shared with concurrent callers.
+	buf := make([]byte, size)
+	n, err := r.dna.ReadAt(buf, rec.dnaOffset+byteOffset)
+	if n != size {
+		return nil, fmt.Errorf("Failed to read %d dna bytes: %s", size, err)
+	} else if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("Failed to read dna bytes: %s", err)
 	}
 
-	buf := make([]byte, defaultBufSize)
-
-	i := 0
-	for c := 0; c < chunks; c++ {
-		sz := defaultBufSize
-		if i+defaultBufSize > size {
-			sz = size % defaultBufSize
-		}
-		n, err := r.reader.Read(buf[0:sz])
-		if n != sz {
-			return nil, fmt.Errorf("Failed to read %d dna bytes: %s", sz, err)
-		} else if err != nil && err != io.EOF {
-			return nil, fmt.Errorf("Failed to read dna bytes: %s", err)
-		}
-
-		for k := 0; k < n; k++ {
-			base := buf[k]
-			for j := 3; j >= 0; j-- {
-				dna[(i*4)+j] = BYTES2NT[int(base&0x3)]
-				base >>= 2
-			}
-			i++
+	dna := make([]byte, size*4)
+	for i, base := range buf {
+		for j := 3; j >= 0; j-- {
+			dna[(i*4)+j] = BYTES2NT[int(base&0x3)]
+			base >>= 2
 		}
 	}
 
@@ -367,12 +405,13 @@ func (r *Reader) ReadRange(name string, start, end int) ([]byte, error) {
 			idx = 0
 		}
 		for i := 0; i < cnt; i++ {
-			// Faster lower case.. see: https://groups.google.com/forum/#!topic/golang-nuts/Il2DX4xpW3w
-			seq[idx] = seq[idx] + 32 // ('a' - 'A')
-			idx++
+			// moved this up because a few situations caused a panic due to index out of range from get-go.
 			if idx >= len(seq) {
 				break
 			}
+			// Faster lower case.. see: https://groups.google.com/forum/#!topic/golang-nuts/Il2DX4xpW3w
+			seq[idx] = seq[idx] + 32 // ('a' - 'A')
+			idx++
 		}
 	}
 
@@ -382,8 +421,35 @@ func (r *Reader) ReadRange(name string, start, end int) ([]byte, error) {
 // NewReader returns a new TwoBit file reader which reads from r
 func NewReader(r io.ReadSeeker) (*Reader, error) {
 	tb := new(Reader)
-	tb.reader = r
-	err := tb.parseHeader()
+
+	compressed, err := isBGZF(r)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to probe BGZF header: %s", err)
+	}
+
+	if compressed {
+		tb.bgzf, err = newBGZFReader(r)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to open BGZF stream: %s", err)
+		}
+		tb.reader = tb.bgzf
+	} else {
+		tb.reader = r
+	}
+
+	// Prefer the reader's own ReadAt (e.g. *os.File, *bytes.Reader) for
+	// lock-free concurrent access to the DNA payload. Readers that only
+	// support Seek (such as our BGZF decompressor) fall back to a
+	// mutex-serialized adapter: still goroutine-safe, just not parallel.
+	if ra, ok := tb.reader.(io.ReaderAt); ok {
+		tb.dna = ra
+	} else {
+		tb.dna = &lockedReaderAt{rs: tb.reader}
+	}
+
+	tb.records = make(map[string]*seqRecord)
+
+	err = tb.parseHeader()
 	if err != nil {
 		return nil, err
 	}
@@ -398,7 +464,7 @@ func NewReader(r io.ReadSeeker) (*Reader, error) {
 
 // Length - Returns the length for sequence with name
 func (r *Reader) Length(name string) (int, error) {
-	rec, err := r.parseRecord(name, false)
+	rec, err := r.parseRecord(name)
 	if err != nil {
 		return -1, err
 	}
@@ -408,7 +474,7 @@ func (r *Reader) Length(name string) (int, error) {
 
 // LengthNoN - Returns the length for sequence with name but does not count Ns
 func (r *Reader) LengthNoN(name string) (int, error) {
-	rec, err := r.parseRecord(name, true)
+	rec, err := r.parseRecord(name)
 	if err != nil {
 		return -1, err
 	}
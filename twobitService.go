@@ -1,15 +1,14 @@
 package twobit
 
 import (
-	"bytes"
-	"io/ioutil"
-	"log"
+	"os"
 )
 
 // Service includes all services
 type Service interface {
 	// Genomic Interval-based services
 	GenomicInterval(chr string, start, end int) (string, error)                                                                             // returns genomic sequence using 2-bit (from memory)
+	GenomicIntervals(reqs []IntervalRequest) ([]IntervalResult, error)                                                                      // batched GenomicInterval, grouped and streamed per chromosome for high-throughput lookups
 	GetGenomicIntervalWindow(chr string, start, end int, ref, alt string, windowL, windowR int) (seqRef, seqAlt string, err error)          // returns ref and alt with window to left and right
 	GetGenomicIntervalBound(chr string, start, end int, ref, alt string, intervalStart, intervalEnd int) (seqRef, seqAlt string, err error) // returns ref and alt bound by interval (ex. interval = exon, will return exonRef and exonAlt sequences)
 }
@@ -22,16 +21,14 @@ type twobitResults struct {
 func NewDataService(twobitFile string) (Service, error) {
 	service := new(twobitResults)
 
-	rdr, err := ioutil.ReadFile(twobitFile)
+	f, err := os.Open(twobitFile)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
-	referenceFile := bytes.NewReader(rdr)
-
-	service.tb, err = NewReader(referenceFile)
+	service.tb, err = NewReader(f)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 
 	return service, nil
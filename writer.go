@@ -0,0 +1,242 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Writer writes 2bit files
+type Writer struct {
+	writer  io.WriteSeeker
+	hdr     header
+	names   []string
+	records map[string]*seqRecord
+}
+
+// NewWriter returns a new TwoBit file writer which writes to w. An optional
+// byteOrder may be given to control the byte order of the emitted file;
+// it defaults to binary.LittleEndian, matching UCSC's faToTwoBit on common
+// hosts.
+func NewWriter(w io.WriteSeeker, byteOrder ...binary.ByteOrder) *Writer {
+	bo := binary.ByteOrder(binary.LittleEndian)
+	if len(byteOrder) > 0 {
+		bo = byteOrder[0]
+	}
+
+	return &Writer{
+		writer:  w,
+		hdr:     header{sig: SIG, version: 0, byteOrder: bo},
+		records: make(map[string]*seqRecord),
+	}
+}
+
+// Add adds a named sequence to the writer. N-blocks and masked (lower-case)
+// blocks are detected automatically by scanning runs of 'N'/'n' and
+// lower-case bases, respectively.
+func (w *Writer) Add(name string, seq []byte) error {
+	if _, exists := w.records[name]; exists {
+		return fmt.Errorf("Duplicate sequence name: %s", name)
+	}
+
+	if len(name) > 255 {
+		return fmt.Errorf("Sequence name too long: %s", name)
+	}
+
+	for i, b := range seq {
+		if !isValidBase(b) {
+			return fmt.Errorf("Invalid base %q at position %d in %s: 2bit only supports ACGTN", b, i, name)
+		}
+	}
+
+	rec := &seqRecord{
+		dnaSize:  uint32(len(seq)),
+		nBlocks:  scanBlocks(seq, isN),
+		mBlocks:  scanBlocks(seq, isLower),
+		sequence: packSequence(seq),
+	}
+
+	w.names = append(w.names, name)
+	w.records[name] = rec
+
+	return nil
+}
+
+// Close writes out the header, file index and per-sequence records and
+// flushes them to the underlying writer. The file index offsets are
+// patched in once the size of every record is known.
+func (w *Writer) Close() error {
+	bo := w.hdr.byteOrder
+
+	hdr := make([]byte, 16)
+	bo.PutUint32(hdr[0:4], w.hdr.sig)
+	bo.PutUint32(hdr[4:8], w.hdr.version)
+	bo.PutUint32(hdr[8:12], uint32(len(w.names)))
+	bo.PutUint32(hdr[12:16], 0)
+
+	if _, err := w.writer.Write(hdr); err != nil {
+		return fmt.Errorf("Failed to write header: %s", err)
+	}
+
+	offsetPos := make([]int64, len(w.names))
+
+	for i, name := range w.names {
+		if _, err := w.writer.Write([]byte{byte(len(name))}); err != nil {
+			return fmt.Errorf("Failed to write file index: %s", err)
+		}
+		if _, err := w.writer.Write([]byte(name)); err != nil {
+			return fmt.Errorf("Failed to write file index: %s", err)
+		}
+
+		pos, err := w.writer.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return fmt.Errorf("Failed to write file index: %s", err)
+		}
+		offsetPos[i] = pos
+
+		if _, err := w.writer.Write(make([]byte, 4)); err != nil {
+			return fmt.Errorf("Failed to write file index: %s", err)
+		}
+	}
+
+	offsets := make([]uint32, len(w.names))
+
+	for i, name := range w.names {
+		pos, err := w.writer.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return fmt.Errorf("Failed to write record %s: %s", name, err)
+		}
+		offsets[i] = uint32(pos)
+
+		if err := writeRecord(w.writer, bo, w.records[name]); err != nil {
+			return fmt.Errorf("Failed to write record %s: %s", name, err)
+		}
+	}
+
+	buf := make([]byte, 4)
+	for i, pos := range offsetPos {
+		bo.PutUint32(buf, offsets[i])
+		if _, err := w.writer.Seek(pos, io.SeekStart); err != nil {
+			return fmt.Errorf("Failed to patch file index: %s", err)
+		}
+		if _, err := w.writer.Write(buf); err != nil {
+			return fmt.Errorf("Failed to patch file index: %s", err)
+		}
+	}
+
+	return nil
+}
+
+// writeRecord writes a single seqRecord's dnaSize, block coordinates,
+// reserved field and packed DNA.
+func writeRecord(w io.Writer, bo binary.ByteOrder, rec *seqRecord) error {
+	buf := make([]byte, 4)
+	bo.PutUint32(buf, rec.dnaSize)
+	if _, err := w.Write(buf); err != nil {
+		return err
+	}
+
+	if err := writeBlockCoords(w, bo, rec.nBlocks); err != nil {
+		return fmt.Errorf("Failed to write nBlocks: %s", err)
+	}
+
+	if err := writeBlockCoords(w, bo, rec.mBlocks); err != nil {
+		return fmt.Errorf("Failed to write mBlocks: %s", err)
+	}
+
+	bo.PutUint32(buf, 0)
+	if _, err := w.Write(buf); err != nil {
+		return err
+	}
+
+	_, err := w.Write(rec.sequence)
+	return err
+}
+
+// writeBlockCoords writes a blockCount followed by the block starts and
+// block sizes, mirroring the layout parseBlockCoords reads back.
+func writeBlockCoords(w io.Writer, bo binary.ByteOrder, blocks []*Block) error {
+	buf := make([]byte, 4)
+	bo.PutUint32(buf, uint32(len(blocks)))
+	if _, err := w.Write(buf); err != nil {
+		return err
+	}
+
+	for _, b := range blocks {
+		bo.PutUint32(buf, uint32(b.start))
+		if _, err := w.Write(buf); err != nil {
+			return err
+		}
+	}
+
+	for _, b := range blocks {
+		bo.PutUint32(buf, uint32(b.count))
+		if _, err := w.Write(buf); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// scanBlocks finds runs of consecutive bytes in seq matching match and
+// returns them as Blocks.
+func scanBlocks(seq []byte, match func(byte) bool) []*Block {
+	var blocks []*Block
+
+	start := -1
+	for i, b := range seq {
+		if match(b) {
+			if start == -1 {
+				start = i
+			}
+		} else if start != -1 {
+			blocks = append(blocks, &Block{start: start, count: i - start})
+			start = -1
+		}
+	}
+	if start != -1 {
+		blocks = append(blocks, &Block{start: start, count: len(seq) - start})
+	}
+
+	return blocks
+}
+
+func isN(b byte) bool {
+	return b == BASE_N || b == BASE_N+32
+}
+
+func isLower(b byte) bool {
+	return b >= 'a' && b <= 'z'
+}
+
+// isValidBase reports whether b is one of the bases the 2bit format can
+// represent (A, C, G, T or N, either case). NT2BYTES maps everything else
+// to 0 same as BASE_T, so callers must reject invalid bytes up front
+// instead of letting them silently pack as T.
+func isValidBase(b byte) bool {
+	switch b {
+	case BASE_A, BASE_C, BASE_G, BASE_T, BASE_N,
+		BASE_A + 32, BASE_C + 32, BASE_G + 32, BASE_T + 32, BASE_N + 32:
+		return true
+	default:
+		return false
+	}
+}
+
+// packSequence packs a DNA sequence 2 bits per base using NT2BYTES, with
+// the first base of each group of 4 occupying the top nibble.
+func packSequence(seq []byte) []byte {
+	packed := make([]byte, packedSize(len(seq)))
+
+	for i, b := range seq {
+		shift := uint(3-(i%4)) * 2
+		packed[i/4] |= NT2BYTES[b] << shift
+	}
+
+	return packed
+}
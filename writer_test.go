@@ -0,0 +1,109 @@
+// Copyright 2015 Andrew E. Bruno. All rights reserved.
+// Use of this source code is governed by a BSD style
+// license that can be found in the LICENSE file.
+
+package twobit
+
+import (
+	"io"
+	"os"
+	"testing"
+)
+
+func TestWriterRoundTrip(t *testing.T) {
+	seqs := map[string]string{
+		"chr1": "ACGTACGTNNNNacgtACGTnnnnACGTACGTAC",
+		"chr2": "NNNNNNNNNNACGTACGTacgtacgtACGTTTTTGGGGCCCCAAAA",
+	}
+
+	f, err := os.CreateTemp("", "twobit-writer-test-*.2bit")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %s", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	w := NewWriter(f)
+	for _, name := range []string{"chr1", "chr2"} {
+		if err := w.Add(name, []byte(seqs[name])); err != nil {
+			t.Fatalf("Failed to add %s: %s", name, err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Failed to close writer: %s", err)
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatalf("Failed to seek temp file: %s", err)
+	}
+
+	r, err := NewReader(f)
+	if err != nil {
+		t.Fatalf("Failed to read back twobit file: %s", err)
+	}
+
+	if r.Count() != len(seqs) {
+		t.Errorf("expected %d sequences got %d", len(seqs), r.Count())
+	}
+
+	for name, expected := range seqs {
+		got, err := r.Read(name)
+		if err != nil {
+			t.Fatalf("Failed to read %s: %s", name, err)
+		}
+		if string(got) != expected {
+			t.Errorf("%s: expected %s got %s", name, expected, got)
+		}
+	}
+}
+
+func TestWriterReadRange(t *testing.T) {
+	seq := "ACGTACGTNNNNacgtACGTnnnnACGTACGTAC"
+
+	f, err := os.CreateTemp("", "twobit-writer-test-*.2bit")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %s", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	w := NewWriter(f)
+	if err := w.Add("chr1", []byte(seq)); err != nil {
+		t.Fatalf("Failed to add sequence: %s", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Failed to close writer: %s", err)
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		t.Fatalf("Failed to seek temp file: %s", err)
+	}
+
+	r, err := NewReader(f)
+	if err != nil {
+		t.Fatalf("Failed to read back twobit file: %s", err)
+	}
+
+	got, err := r.ReadRange("chr1", 4, 16)
+	if err != nil {
+		t.Fatalf("Failed to read range: %s", err)
+	}
+
+	if string(got) != seq[4:16] {
+		t.Errorf("expected %s got %s", seq[4:16], got)
+	}
+}
+
+func TestWriterAddInvalidBase(t *testing.T) {
+	f, err := os.CreateTemp("", "twobit-writer-test-*.2bit")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %s", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	w := NewWriter(f)
+	if err := w.Add("chr1", []byte("ACRYSW")); err == nil {
+		t.Fatalf("expected an error adding a sequence with non-ACGTN bases")
+	}
+}